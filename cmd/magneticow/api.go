@@ -1,29 +1,67 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/boramalper/magnetico/pkg/persistence"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
-func apiTorrentsHandler(w http.ResponseWriter, r *http.Request) {
+var apiTorrentsHandler = instrumentedHandler("torrents", func(w http.ResponseWriter, r *http.Request) {
+	tq, orderBy, err := parseTorrentsQ(r)
+	if err != nil {
+		respondError(w, 400, err.Error())
+		return
+	}
+
+	if wantsNDJSON(r) {
+		streamTorrentsNDJSON(r.Context(), w, *tq.Query, *tq.Epoch, orderBy, *tq.Ascending, tq.LastOrderedValue, tq.LastID)
+		return
+	}
+
+	queryStart := time.Now()
+	torrents, err := database.QueryTorrents(
+		*tq.Query, *tq.Epoch, orderBy,
+		*tq.Ascending, N_TORRENTS, tq.LastOrderedValue, tq.LastID)
+	observeQueryTorrents(orderByLabel(orderBy), *tq.Query != "", tq.LastOrderedValue != nil, time.Since(queryStart))
+	if err != nil {
+		respondError(w, 400, "query error: %s", err.Error())
+		return
+	}
+
+	jm, err := json.Marshal(torrents)
+	if err != nil {
+		respondError(w, 500, "json marshalling error: %s", err.Error())
+		return
+	}
+
+	if _, err = w.Write(jm); err != nil {
+		zap.L().Warn("couldn't write http.ResponseWriter", zap.Error(err))
+	}
+})
+
+// parseTorrentsQ decodes and validates the query parameters shared by
+// apiTorrentsHandler and apiFeedHandler: the TorrentsQ itself, defaulted and
+// range-checked, and the persistence.OrderingCriteria it resolves to.
+func parseTorrentsQ(r *http.Request) (*TorrentsQ, persistence.OrderingCriteria, error) {
 	// @lastOrderedValue AND @lastID are either both supplied or neither of them should be supplied
 	// at all; and if that is NOT the case, then return an error.
-	if q := r.URL.Query(); !(
-		(q.Get("lastOrderedValue") != "" && q.Get("lastID") != "") ||
-			(q.Get("lastOrderedValue") == "" && q.Get("lastID") == "")) {
-		respondError(w, 400, "`lastOrderedValue`, `lastID` must be supplied altogether, if supplied.")
-		return
+	if q := r.URL.Query(); !((q.Get("lastOrderedValue") != "" && q.Get("lastID") != "") ||
+		(q.Get("lastOrderedValue") == "" && q.Get("lastID") == "")) {
+		return nil, 0, fmt.Errorf("`lastOrderedValue`, `lastID` must be supplied altogether, if supplied.")
 	}
 
 	var tq TorrentsQ
 	if err := decoder.Decode(&tq, r.URL.Query()); err != nil {
-		respondError(w, 400, "error while parsing the URL: %s", err.Error())
-		return
+		return nil, 0, fmt.Errorf("error while parsing the URL: %s", err.Error())
 	}
 
 	if tq.Query == nil {
@@ -33,15 +71,13 @@ func apiTorrentsHandler(w http.ResponseWriter, r *http.Request) {
 
 	if tq.Epoch == nil {
 		tq.Epoch = new(int64)
-		*tq.Epoch = time.Now().Unix()  // epoch, if not supplied, is NOW.
+		*tq.Epoch = time.Now().Unix() // epoch, if not supplied, is NOW.
 	} else if *tq.Epoch <= 0 {
-		respondError(w, 400, "epoch must be greater than 0")
-		return
+		return nil, 0, fmt.Errorf("epoch must be greater than 0")
 	}
 
 	if tq.LastID != nil && *tq.LastID < 0 {
-		respondError(w, 400, "lastID has to be greater than or equal to zero")
-		return
+		return nil, 0, fmt.Errorf("lastID has to be greater than or equal to zero")
 	}
 
 	if tq.Ascending == nil {
@@ -60,21 +96,88 @@ func apiTorrentsHandler(w http.ResponseWriter, r *http.Request) {
 		var err error
 		orderBy, err = parseOrderBy(*tq.OrderBy)
 		if err != nil {
-			respondError(w, 400, err.Error())
+			return nil, 0, err
+		}
+	}
+
+	return &tq, orderBy, nil
+}
+
+// wantsNDJSON reports whether the client asked for the streamed,
+// one-torrent-per-line response mode instead of the default JSON array,
+// either via ?format=ndjson or an Accept: application/x-ndjson header.
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// streamTorrentsNDJSON writes one JSON-encoded torrent per line as they
+// arrive from persistence.QueryTorrentsChan, flushing periodically so
+// large result sets don't have to be buffered in full before the first
+// byte reaches the client. Every return path cancels ctx first, so the
+// producer goroutine (and its *sql.Rows/DB connection) is released even
+// when we give up reading early, e.g. because the client disconnected.
+func streamTorrentsNDJSON(ctx context.Context, w http.ResponseWriter, query string, epoch int64, orderBy persistence.OrderingCriteria, ascending bool, lastOrderedValue *float64, lastID *uint64) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queryStart := time.Now()
+	defer func() {
+		observeQueryTorrents(orderByLabel(orderBy), query != "", lastOrderedValue != nil, time.Since(queryStart))
+	}()
+
+	torrents, errs := database.QueryTorrentsChan(ctx, query, epoch, orderBy, ascending, N_TORRENTS, lastOrderedValue, lastID)
+	for torrents != nil || errs != nil {
+		select {
+		case torrent, ok := <-torrents:
+			if !ok {
+				torrents = nil
+				continue
+			}
+			if err := enc.Encode(torrent); err != nil {
+				zap.L().Warn("couldn't write http.ResponseWriter", zap.Error(err))
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			zap.L().Warn("error while streaming torrents", zap.Error(err))
 			return
 		}
 	}
+}
+
+var apiTorrentsInfohashHandler = instrumentedHandler("torrents_infohash", func(w http.ResponseWriter, r *http.Request) {
+	infoHash, err := parseInfoHash(mux.Vars(r)["infohash"])
+	if err != nil {
+		respondError(w, 400, "error while parsing infohash: %s", err.Error())
+		return
+	}
 
-	torrents, err := database.QueryTorrents(
-		*tq.Query, *tq.Epoch, orderBy,
-		*tq.Ascending, N_TORRENTS, tq.LastOrderedValue, tq.LastID)
+	torrent, err := database.GetTorrent(infoHash)
 	if err != nil {
 		respondError(w, 400, "query error: %s", err.Error())
 		return
 	}
+	if torrent == nil {
+		respondError(w, 404, "torrent not found")
+		return
+	}
 
-	// TODO: use plain Marshal
-	jm, err := json.MarshalIndent(torrents, "", "  ")
+	jm, err := json.Marshal(torrent)
 	if err != nil {
 		respondError(w, 500, "json marshalling error: %s", err.Error())
 		return
@@ -83,20 +186,147 @@ func apiTorrentsHandler(w http.ResponseWriter, r *http.Request) {
 	if _, err = w.Write(jm); err != nil {
 		zap.L().Warn("couldn't write http.ResponseWriter", zap.Error(err))
 	}
-}
+})
 
-func apiTorrentsInfohashHandler(w http.ResponseWriter, r *http.Request) {
+var apiFilesInfohashHandler = instrumentedHandler("files_infohash", func(w http.ResponseWriter, r *http.Request) {
+	infoHash, err := parseInfoHash(mux.Vars(r)["infohash"])
+	if err != nil {
+		respondError(w, 400, "error while parsing infohash: %s", err.Error())
+		return
+	}
 
+	files, err := database.GetFiles(infoHash)
+	if err != nil {
+		respondError(w, 400, "query error: %s", err.Error())
+		return
+	}
+
+	var jm []byte
+	if r.URL.Query().Get("format") == "tree" {
+		jm, err = json.Marshal(fileTree(files))
+	} else {
+		jm, err = json.Marshal(files)
+	}
+	if err != nil {
+		respondError(w, 500, "json marshalling error: %s", err.Error())
+		return
+	}
+
+	if _, err = w.Write(jm); err != nil {
+		zap.L().Warn("couldn't write http.ResponseWriter", zap.Error(err))
+	}
+})
+
+// fileNode is a single entry in the nested directory structure returned by
+// apiFilesInfohashHandler when ?format=tree is requested.
+type fileNode struct {
+	Name     string               `json:"name"`
+	Size     int64                `json:"size,omitempty"`
+	Children map[string]*fileNode `json:"children,omitempty"`
 }
 
-func apiFilesInfohashHandler(w http.ResponseWriter, r *http.Request) {
+// fileTree turns the flat, slash-separated paths persistence.File.Path
+// gives us into a nested directory structure, so UIs can render it without
+// having to split paths themselves. Every node gets an initialized Children
+// map, even leaves, because a torrent's file list isn't guaranteed to be
+// collision-free: nothing stops a path like "readme" from appearing
+// alongside "readme/license.txt", which turns the "readme" leaf into an
+// intermediate node partway through the walk.
+func fileTree(files []persistence.File) *fileNode {
+	root := &fileNode{Name: "/", Children: make(map[string]*fileNode)}
+
+	for _, file := range files {
+		node := root
+		parts := strings.Split(strings.TrimPrefix(file.Path, "/"), "/")
+		for _, part := range parts {
+			child, ok := node.Children[part]
+			if !ok {
+				child = &fileNode{Name: part, Children: make(map[string]*fileNode)}
+				node.Children[part] = child
+			}
+			node = child
+		}
+		node.Size = file.Size
+	}
 
+	return root
 }
 
-func apiStatisticsHandler(w http.ResponseWriter, r *http.Request) {
+// parseInfoHash decodes a hex-encoded, 20-byte BitTorrent infohash as it
+// appears in the API's URL paths (e.g. /api/v0.1/torrents/{infohash}).
+func parseInfoHash(s string) ([]byte, error) {
+	infoHash, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(infoHash) != 20 {
+		return nil, fmt.Errorf("infohash must be 20 bytes, got %d", len(infoHash))
+	}
+	return infoHash, nil
+}
 
+// statisticsGranularities are the bucket sizes apiStatisticsHandler accepts
+// for the `bucket` query parameter.
+var statisticsGranularities = map[string]bool{
+	"hour":  true,
+	"day":   true,
+	"month": true,
 }
 
+var apiStatisticsHandler = instrumentedHandler("statistics", func(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	bucket := q.Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+	if !statisticsGranularities[bucket] {
+		respondError(w, 400, "bucket must be one of hour, day, month")
+		return
+	}
+
+	to := time.Now().Unix()
+	if s := q.Get("to"); s != "" {
+		parsed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			respondError(w, 400, "error while parsing `to`: %s", err.Error())
+			return
+		}
+		to = parsed
+	}
+
+	from := to - 30*24*60*60 // 30 days, by default.
+	if s := q.Get("from"); s != "" {
+		parsed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			respondError(w, 400, "error while parsing `from`: %s", err.Error())
+			return
+		}
+		from = parsed
+	}
+
+	if from >= to {
+		respondError(w, 400, "`from` must be before `to`")
+		return
+	}
+
+	statistics, err := database.GetStatistics(from, to, bucket)
+	if err != nil {
+		respondError(w, 400, "query error: %s", err.Error())
+		return
+	}
+
+	jm, err := json.Marshal(statistics)
+	if err != nil {
+		respondError(w, 500, "json marshalling error: %s", err.Error())
+		return
+	}
+
+	if _, err = w.Write(jm); err != nil {
+		zap.L().Warn("couldn't write http.ResponseWriter", zap.Error(err))
+	}
+})
+
 func parseOrderBy(s string) (persistence.OrderingCriteria, error) {
 	switch s {
 	case "TOTAL_SIZE":
@@ -120,4 +350,4 @@ func parseOrderBy(s string) (persistence.OrderingCriteria, error) {
 	default:
 		return persistence.ByDiscoveredOn, fmt.Errorf("unknown orderBy string: %s", s)
 	}
-}
\ No newline at end of file
+}
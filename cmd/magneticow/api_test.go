@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/boramalper/magnetico/pkg/persistence"
+)
+
+func TestFileTree(t *testing.T) {
+	cases := []struct {
+		name  string
+		files []persistence.File
+	}{
+		{
+			name:  "empty",
+			files: nil,
+		},
+		{
+			name: "flat",
+			files: []persistence.File{
+				{Path: "readme.txt", Size: 10},
+				{Path: "movie.mkv", Size: 1000},
+			},
+		},
+		{
+			name: "nested",
+			files: []persistence.File{
+				{Path: "subs/en.srt", Size: 5},
+				{Path: "subs/fr.srt", Size: 6},
+			},
+		},
+		{
+			name: "leaf becomes a directory",
+			files: []persistence.File{
+				{Path: "readme", Size: 10},
+				{Path: "readme/license.txt", Size: 20},
+			},
+		},
+		{
+			name: "directory becomes a leaf",
+			files: []persistence.File{
+				{Path: "readme/license.txt", Size: 20},
+				{Path: "readme", Size: 10},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("fileTree panicked: %v", r)
+				}
+			}()
+			root := fileTree(c.files)
+			if root == nil {
+				t.Fatal("fileTree returned nil root")
+			}
+			if root.Children == nil {
+				t.Fatal("root.Children is nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/boramalper/magnetico/pkg/persistence"
+	"go.uber.org/zap"
+)
+
+// publicTrackers is appended to every magnet link we hand out so that feed
+// readers can start leeching immediately instead of relying on DHT alone.
+var publicTrackers = []string{
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://tracker.openbittorrent.com:6969/announce",
+	"udp://exodus.desync.com:6969/announce",
+	"udp://tracker.torrent.eu.org:451/announce",
+}
+
+// rssFeed and friends mirror the minimal subset of the RSS 2.0 spec that
+// torrent indexers actually rely on: title, link, a GUID, and an enclosure
+// carrying the payload size.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Desc  string    `xml:"description"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Link      string       `xml:"link"`
+	Guid      rssGuid      `xml:"guid"`
+	PubDate   string       `xml:"pubDate"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+}
+
+// rssGuid carries isPermaLink="false" alongside the torrent's infohash so
+// feed readers don't try to dereference it as a URL, which is what a bare
+// <guid> implies per the RSS 2.0 spec.
+type rssGuid struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// atomFeed is the Atom 1.0 counterpart of rssFeed.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// apiFeedHandler serves an RSS 2.0 (default) or Atom 1.0 (?format=atom) feed
+// of the most recently discovered torrents, honouring the same `query`,
+// `orderBy`, and `epoch` parameters as apiTorrentsHandler.
+var apiFeedHandler = instrumentedHandler("feed", func(w http.ResponseWriter, r *http.Request) {
+	tq, orderBy, err := parseTorrentsQ(r)
+	if err != nil {
+		respondError(w, 400, err.Error())
+		return
+	}
+
+	queryStart := time.Now()
+	torrents, err := database.QueryTorrents(
+		*tq.Query, *tq.Epoch, orderBy,
+		*tq.Ascending, N_TORRENTS, tq.LastOrderedValue, tq.LastID)
+	observeQueryTorrents(orderByLabel(orderBy), *tq.Query != "", tq.LastOrderedValue != nil, time.Since(queryStart))
+	if err != nil {
+		respondError(w, 400, "query error: %s", err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "atom" {
+		writeAtomFeed(w, torrents)
+		return
+	}
+	writeRSSFeed(w, torrents)
+})
+
+func writeRSSFeed(w http.ResponseWriter, torrents []persistence.Torrent) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title: "magnetico recent torrents",
+			Link:  "/",
+			Desc:  "Recently discovered torrents matching the given query.",
+		},
+	}
+
+	for _, t := range torrents {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:   t.Name,
+			Link:    magnetLink(t.InfoHash, t.Name),
+			Guid:    rssGuid{Value: fmt.Sprintf("%x", t.InfoHash), IsPermaLink: "false"},
+			PubDate: time.Unix(t.DiscoveredOn, 0).UTC().Format(time.RFC1123Z),
+			Enclosure: rssEnclosure{
+				URL:    magnetLink(t.InfoHash, t.Name),
+				Length: t.Size,
+				Type:   "application/x-bittorrent",
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	if err := writeXML(w, feed); err != nil {
+		zap.L().Warn("couldn't write RSS feed", zap.Error(err))
+	}
+}
+
+func writeAtomFeed(w http.ResponseWriter, torrents []persistence.Torrent) {
+	feed := atomFeed{
+		Title:   "magnetico recent torrents",
+		ID:      "urn:magnetico:feed",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for _, t := range torrents {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   t.Name,
+			ID:      fmt.Sprintf("urn:btih:%x", t.InfoHash),
+			Updated: time.Unix(t.DiscoveredOn, 0).UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: magnetLink(t.InfoHash, t.Name), Rel: "alternate"},
+			Summary: fmt.Sprintf("%d bytes", t.Size),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if err := writeXML(w, feed); err != nil {
+		zap.L().Warn("couldn't write Atom feed", zap.Error(err))
+	}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) error {
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(v)
+}
+
+// magnetLink builds a magnet URI for the given infohash, name, and the
+// common public trackers so the feed can be opened directly in a client.
+func magnetLink(infoHash []byte, name string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "magnet:?xt=urn:btih:%x&dn=%s", infoHash, url.QueryEscape(name))
+	for _, tr := range publicTrackers {
+		fmt.Fprintf(&sb, "&tr=%s", url.QueryEscape(tr))
+	}
+	return sb.String()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/boramalper/magnetico/pkg/persistence"
+	"go.uber.org/zap"
+)
+
+// categoryExtensions maps the file-extension-based buckets the UI offers
+// (and that categories filtering relies on) to the extensions that
+// belong to them. Extensions are matched case-insensitively and without the
+// leading dot.
+var categoryExtensions = map[string][]string{
+	"audio":       {"mp3", "flac", "wav", "aac", "ogg", "m4a", "wma"},
+	"video":       {"mp4", "mkv", "avi", "mov", "wmv", "flv", "webm"},
+	"image":       {"jpg", "jpeg", "png", "gif", "bmp", "tiff", "webp"},
+	"document":    {"pdf", "epub", "doc", "docx", "txt", "mobi", "azw3"},
+	"archive":     {"zip", "rar", "7z", "tar", "gz", "bz2", "xz"},
+	"application": {"exe", "msi", "apk", "deb", "rpm", "dmg"},
+}
+
+// TorrentsFilterQ is the JSON counterpart of TorrentsQ: it is decoded from
+// the body of POST /api/v0.1/torrents and supports filters that cannot be
+// expressed as a single free-text query string.
+type TorrentsFilterQ struct {
+	Query     string `json:"query"`
+	OrderBy   string `json:"orderBy"`
+	Ascending *bool  `json:"ascending"`
+
+	LastOrderedValue *float64 `json:"lastOrderedValue"`
+	LastID           *uint64  `json:"lastID"`
+
+	// MinSize and MaxSize bound the total size of the torrent, in bytes.
+	MinSize *int64 `json:"minSize"`
+	MaxSize *int64 `json:"maxSize"`
+
+	// FromDate and ToDate bound discoveredOn as Unix epochs; MaxAgeDays is
+	// a convenience alternative to FromDate expressed relative to now.
+	FromDate   *int64 `json:"fromDate"`
+	ToDate     *int64 `json:"toDate"`
+	MaxAgeDays *int   `json:"maxAgeDays"`
+
+	// NameLike is a list of substrings that must ALL appear in the
+	// torrent's name (case-insensitive, AND-combined).
+	NameLike []string `json:"nameLike"`
+
+	// NotNull and Null name columns (e.g. "n_seeders") that must or must
+	// not be set on a matching torrent.
+	NotNull []string `json:"notNull"`
+	Null    []string `json:"null"`
+
+	// Categories is a CSV-free list of the file-extension-based buckets
+	// defined in categoryExtensions (e.g. "video", "audio").
+	Categories []string `json:"categories"`
+}
+
+// toPersistenceFilter validates tfq and translates it into the
+// persistence.TorrentsFilter the persistence layer understands.
+func (tfq *TorrentsFilterQ) toPersistenceFilter() (*persistence.TorrentsFilter, error) {
+	for _, column := range append(append([]string{}, tfq.NotNull...), tfq.Null...) {
+		if !persistence.AllowedNullableColumns[column] {
+			return nil, fmt.Errorf("unknown column for notNull/null: %s", column)
+		}
+	}
+
+	filter := &persistence.TorrentsFilter{
+		Query:     tfq.Query,
+		Epoch:     time.Now().Unix(),
+		Ascending: true,
+		MinSize:   tfq.MinSize,
+		MaxSize:   tfq.MaxSize,
+		NotNull:   tfq.NotNull,
+		Null:      tfq.Null,
+	}
+
+	if tfq.Ascending != nil {
+		filter.Ascending = *tfq.Ascending
+	}
+
+	if tfq.OrderBy == "" {
+		if tfq.Query == "" {
+			filter.OrderBy = persistence.ByDiscoveredOn
+		} else {
+			filter.OrderBy = persistence.ByRelevance
+		}
+	} else {
+		orderBy, err := parseOrderBy(tfq.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+		filter.OrderBy = orderBy
+	}
+
+	switch {
+	case tfq.FromDate != nil && tfq.MaxAgeDays != nil:
+		return nil, fmt.Errorf("fromDate and maxAgeDays are mutually exclusive")
+	case tfq.MaxAgeDays != nil:
+		from := time.Now().AddDate(0, 0, -*tfq.MaxAgeDays).Unix()
+		filter.FromDate = &from
+	case tfq.FromDate != nil:
+		filter.FromDate = tfq.FromDate
+	}
+	filter.ToDate = tfq.ToDate
+
+	for _, needle := range tfq.NameLike {
+		if strings.TrimSpace(needle) == "" {
+			return nil, fmt.Errorf("nameLike entries must not be empty")
+		}
+		filter.NameLike = append(filter.NameLike, needle)
+	}
+
+	for _, category := range tfq.Categories {
+		extensions, ok := categoryExtensions[strings.ToLower(category)]
+		if !ok {
+			return nil, fmt.Errorf("unknown category: %s", category)
+		}
+		filter.Extensions = append(filter.Extensions, extensions...)
+	}
+
+	if tfq.LastOrderedValue != nil && tfq.LastID != nil {
+		filter.LastOrderedValue = tfq.LastOrderedValue
+		filter.LastID = tfq.LastID
+	} else if tfq.LastOrderedValue != nil || tfq.LastID != nil {
+		return nil, fmt.Errorf("lastOrderedValue, lastID must be supplied altogether, if supplied")
+	}
+
+	return filter, nil
+}
+
+// apiTorrentsPostHandler is the POST /api/v0.1/torrents counterpart of
+// apiTorrentsHandler: it accepts a JSON body decoded into a TorrentsFilterQ
+// and supports filters that cannot be expressed in a GET query string.
+var apiTorrentsPostHandler = instrumentedHandler("torrents_post", func(w http.ResponseWriter, r *http.Request) {
+	var tfq TorrentsFilterQ
+	if err := json.NewDecoder(r.Body).Decode(&tfq); err != nil {
+		respondError(w, 400, "error while parsing the request body: %s", err.Error())
+		return
+	}
+
+	filter, err := tfq.toPersistenceFilter()
+	if err != nil {
+		respondError(w, 400, "%s", err.Error())
+		return
+	}
+
+	queryStart := time.Now()
+	torrents, err := database.QueryTorrentsFiltered(filter, N_TORRENTS)
+	observeQueryTorrents(orderByLabel(filter.OrderBy), filter.Query != "", filter.LastOrderedValue != nil, time.Since(queryStart))
+	if err != nil {
+		respondError(w, 400, "query error: %s", err.Error())
+		return
+	}
+
+	jm, err := json.MarshalIndent(torrents, "", "  ")
+	if err != nil {
+		respondError(w, 500, "json marshalling error: %s", err.Error())
+		return
+	}
+
+	if _, err = w.Write(jm); err != nil {
+		zap.L().Warn("couldn't write http.ResponseWriter", zap.Error(err))
+	}
+})
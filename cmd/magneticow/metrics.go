@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/boramalper/magnetico/pkg/persistence"
+)
+
+// adminToken gates the detailed, per-query-type metrics breakdown exposed
+// by apiMetricsBreakdownHandler. It is empty (i.e. the endpoint disabled)
+// unless set via -admin-token at startup.
+var adminToken string
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magneticow_http_requests_total",
+		Help: "Total number of HTTP requests handled by the API, by endpoint and status code.",
+	}, []string{"endpoint", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "magneticow_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests handled by the API, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+)
+
+// breakdownRegistry is a registry of its own, separate from the default one
+// apiMetricsHandler serves, so the admin_token gate on
+// apiMetricsBreakdownHandler actually controls access to
+// queryTorrentsDuration/queriesByKind instead of them being reachable
+// through the public, unauthenticated /metrics endpoint too.
+var breakdownRegistry = prometheus.NewRegistry()
+
+var (
+	queryTorrentsDuration = promauto.With(breakdownRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "magneticow_query_torrents_duration_seconds",
+		Help:    "Latency of persistence.QueryTorrents calls, by ordering criteria.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"order_by"})
+
+	queriesByKind = promauto.With(breakdownRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "magneticow_queries_by_kind_total",
+		Help: "Number of torrent queries broken down by ordering criteria, whether a free-text query was used, and whether pagination cursors were supplied.",
+	}, []string{"order_by", "has_query", "paginated"})
+)
+
+// instrumentedHandler wraps h so every call increments requestsTotal and
+// observes requestDuration under the given endpoint label. The wrapped
+// ResponseWriter lets us capture the status code the handler ends up
+// writing.
+func instrumentedHandler(endpoint string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusCapturingWriter{ResponseWriter: w, status: http.StatusOK}
+
+		start := time.Now()
+		h(sw, r)
+		requestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+		requestsTotal.WithLabelValues(endpoint, strconv.Itoa(sw.status)).Inc()
+	}
+}
+
+// statusCapturingWriter records the status code passed to WriteHeader so
+// instrumentedHandler can label requestsTotal with it; http.ResponseWriter
+// itself exposes no way to read it back.
+type statusCapturingWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusCapturingWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+// Flush forwards to the wrapped ResponseWriter when it is an http.Flusher,
+// so streamed responses still reach the client progressively.
+func (sw *statusCapturingWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// observeQueryTorrents records the latency of a persistence.QueryTorrents
+// call and tallies it by ordering criteria, whether a free-text query was
+// used, and whether pagination cursors were supplied. Call it around every
+// database.QueryTorrents / QueryTorrentsFiltered / QueryTorrentsChan call
+// site so the breakdown stays accurate as new call sites are added.
+func observeQueryTorrents(orderBy string, hasQuery bool, paginated bool, duration time.Duration) {
+	queryTorrentsDuration.WithLabelValues(orderBy).Observe(duration.Seconds())
+	queriesByKind.WithLabelValues(orderBy, strconv.FormatBool(hasQuery), strconv.FormatBool(paginated)).Inc()
+}
+
+// orderByLabel renders orderBy as the string queryTorrentsDuration and
+// queriesByKind group by, mirroring the names parseOrderBy accepts.
+func orderByLabel(orderBy persistence.OrderingCriteria) string {
+	switch orderBy {
+	case persistence.ByTotalSize:
+		return "TOTAL_SIZE"
+	case persistence.ByDiscoveredOn:
+		return "DISCOVERED_ON"
+	case persistence.ByNFiles:
+		return "N_FILES"
+	case persistence.ByUpdatedOn:
+		return "UPDATED_ON"
+	case persistence.ByNSeeders:
+		return "N_SEEDERS"
+	case persistence.ByNLeechers:
+		return "N_LEECHERS"
+	default:
+		return "RELEVANCE"
+	}
+}
+
+// apiMetricsHandler exposes the default registry (requestsTotal and
+// requestDuration) in Prometheus text format; it requires no authentication, matching the rest of
+// the read-only, public API.
+var apiMetricsHandler = promhttp.Handler().ServeHTTP
+
+// apiMetricsBreakdownHandler serves breakdownRegistry — queryTorrentsDuration
+// and queriesByKind, kept out of the default registry apiMetricsHandler
+// serves — gated on admin_token, since the breakdown can hint at which
+// saved searches are popular.
+var breakdownHandler = promhttp.HandlerFor(breakdownRegistry, promhttp.HandlerOpts{})
+
+func apiMetricsBreakdownHandler(w http.ResponseWriter, r *http.Request) {
+	if adminToken == "" || r.URL.Query().Get("admin_token") != adminToken {
+		respondError(w, 403, "invalid or missing admin_token")
+		return
+	}
+
+	breakdownHandler.ServeHTTP(w, r)
+}
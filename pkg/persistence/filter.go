@@ -0,0 +1,87 @@
+package persistence
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryTorrentsFiltered is QueryTorrents' counterpart for filters that
+// cannot be expressed as a single free-text query string: size/date
+// bounds, AND-combined name substrings, NULL-ness of a column, and
+// file-extension membership.
+func (db *sqlite3Database) QueryTorrentsFiltered(filter *TorrentsFilter, limit uint) ([]Torrent, error) {
+	column := orderByColumn(filter.OrderBy)
+	direction := "DESC"
+	cmp := "<"
+	if filter.Ascending {
+		direction = "ASC"
+		cmp = ">"
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, 8)
+
+	sb.WriteString("SELECT info_hash, name, total_size, discovered_on, n_files, updated_on, n_seeders, n_leechers FROM torrents WHERE discovered_on <= ?")
+	args = append(args, filter.Epoch)
+
+	if filter.Query != "" {
+		sb.WriteString(" AND name LIKE ?")
+		args = append(args, "%"+filter.Query+"%")
+	}
+
+	for _, needle := range filter.NameLike {
+		sb.WriteString(" AND name LIKE ?")
+		args = append(args, "%"+needle+"%")
+	}
+
+	if filter.MinSize != nil {
+		sb.WriteString(" AND total_size >= ?")
+		args = append(args, *filter.MinSize)
+	}
+	if filter.MaxSize != nil {
+		sb.WriteString(" AND total_size <= ?")
+		args = append(args, *filter.MaxSize)
+	}
+
+	if filter.FromDate != nil {
+		sb.WriteString(" AND discovered_on >= ?")
+		args = append(args, *filter.FromDate)
+	}
+	if filter.ToDate != nil {
+		sb.WriteString(" AND discovered_on <= ?")
+		args = append(args, *filter.ToDate)
+	}
+
+	for _, column := range filter.NotNull {
+		if !AllowedNullableColumns[column] {
+			return nil, fmt.Errorf("persistence: unknown column for notNull: %s", column)
+		}
+		sb.WriteString(fmt.Sprintf(" AND %s IS NOT NULL", column))
+	}
+	for _, column := range filter.Null {
+		if !AllowedNullableColumns[column] {
+			return nil, fmt.Errorf("persistence: unknown column for null: %s", column)
+		}
+		sb.WriteString(fmt.Sprintf(" AND %s IS NULL", column))
+	}
+
+	if len(filter.Extensions) > 0 {
+		var extConds []string
+		for _, ext := range filter.Extensions {
+			extConds = append(extConds, "path LIKE ?")
+			args = append(args, "%."+ext)
+		}
+		sb.WriteString(fmt.Sprintf(" AND EXISTS (SELECT 1 FROM files WHERE files.torrent_id = torrents.id AND (%s))",
+			strings.Join(extConds, " OR ")))
+	}
+
+	if filter.LastOrderedValue != nil && filter.LastID != nil {
+		sb.WriteString(fmt.Sprintf(" AND (%s %s ? OR (%s = ? AND id %s ?))", column, cmp, column, cmp))
+		args = append(args, *filter.LastOrderedValue, *filter.LastOrderedValue, *filter.LastID)
+	}
+
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, direction, direction))
+	args = append(args, limit)
+
+	return db.queryTorrents(sb.String(), args...)
+}
@@ -0,0 +1,162 @@
+// Package persistence abstracts over the storage engines magnetico can
+// keep its index in. Everything the crawler discovers, and everything the
+// web API queries, goes through the Database interface defined here.
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// OrderingCriteria determines how QueryTorrents (and its siblings) order
+// their results.
+type OrderingCriteria uint8
+
+const (
+	ByRelevance OrderingCriteria = iota
+	ByTotalSize
+	ByDiscoveredOn
+	ByNFiles
+	ByUpdatedOn
+	ByNSeeders
+	ByNLeechers
+)
+
+// Torrent is a single row of the torrents table.
+type Torrent struct {
+	InfoHash     []byte  `json:"infoHash"`
+	Name         string  `json:"name"`
+	Size         int64   `json:"size"`
+	DiscoveredOn int64   `json:"discoveredOn"`
+	NFiles       uint    `json:"nFiles"`
+	Relevance    float64 `json:"relevance,omitempty"`
+	UpdatedOn    int64   `json:"updatedOn,omitempty"`
+	NSeeders     uint    `json:"nSeeders,omitempty"`
+	NLeechers    uint    `json:"nLeechers,omitempty"`
+}
+
+// File is a single row of the files table, belonging to a Torrent.
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// TorrentsFilter is the expanded filter set accepted by
+// Database.QueryTorrentsFiltered, covering the size/date/name/null/category
+// predicates that a single free-text query string cannot express.
+type TorrentsFilter struct {
+	Query     string
+	Epoch     int64
+	OrderBy   OrderingCriteria
+	Ascending bool
+
+	LastOrderedValue *float64
+	LastID           *uint64
+
+	// MinSize and MaxSize bound the torrent's total size, in bytes.
+	MinSize *int64
+	MaxSize *int64
+
+	// FromDate and ToDate bound DiscoveredOn, as Unix epochs.
+	FromDate *int64
+	ToDate   *int64
+
+	// NameLike is a list of substrings that must ALL appear in the
+	// torrent's name (AND-combined).
+	NameLike []string
+
+	// NotNull and Null name columns (from AllowedNullableColumns) that
+	// must or must not be set on a matching torrent.
+	NotNull []string
+	Null    []string
+
+	// Extensions is a list of file extensions (without the leading dot); a
+	// torrent matches if at least one of its files has one of them.
+	Extensions []string
+}
+
+// StatisticsBucket is a single time-bucketed aggregate row returned by
+// Database.GetStatistics.
+type StatisticsBucket struct {
+	Bucket             string  `json:"bucket"`
+	NTorrents          uint    `json:"nTorrents"`
+	TotalSize          int64   `json:"totalSize"`
+	AvgFilesPerTorrent float64 `json:"avgFilesPerTorrent"`
+}
+
+// AllowedNullableColumns is the allowlist of torrents columns that may be
+// named in TorrentsFilter.NotNull/Null. It exists so user-supplied column
+// names never reach SQL construction unchecked.
+var AllowedNullableColumns = map[string]bool{
+	"updated_on": true,
+	"n_seeders":  true,
+	"n_leechers": true,
+}
+
+// Engine identifies a supported storage backend.
+type Engine uint8
+
+const (
+	Sqlite3 Engine = iota
+)
+
+// Database is the interface every supported storage engine implements; the
+// web API and the crawler only ever talk to a Database, never to a
+// particular engine directly.
+type Database interface {
+	QueryTorrents(query string, epoch int64, orderBy OrderingCriteria, ascending bool, limit uint,
+		lastOrderedValue *float64, lastID *uint64) ([]Torrent, error)
+
+	// QueryTorrentsFiltered is QueryTorrents' counterpart for filters that
+	// cannot be expressed as a single free-text query string.
+	QueryTorrentsFiltered(filter *TorrentsFilter, limit uint) ([]Torrent, error)
+
+	// QueryTorrentsChan is QueryTorrents' streaming counterpart: it starts
+	// fetching in the background and delivers one Torrent at a time, so
+	// callers don't have to hold the whole result set in memory. The
+	// torrent channel is closed once every matching torrent has been
+	// sent, or as soon as an error is sent on the error channel. Cancelling
+	// ctx (e.g. because the client disconnected mid-stream) stops the
+	// producer and releases its *sql.Rows/connection; callers that give up
+	// reading early MUST cancel ctx rather than simply walking away.
+	QueryTorrentsChan(ctx context.Context, query string, epoch int64, orderBy OrderingCriteria, ascending bool, limit uint,
+		lastOrderedValue *float64, lastID *uint64) (<-chan Torrent, <-chan error)
+
+	// GetTorrent returns a single torrent's metadata by its infohash, or
+	// nil if no torrent with that infohash has been discovered.
+	GetTorrent(infoHash []byte) (*Torrent, error)
+
+	// GetFiles returns the file list belonging to the torrent identified
+	// by infoHash.
+	GetFiles(infoHash []byte) ([]File, error)
+
+	// GetStatistics aggregates torrents discovered between from and to
+	// (inclusive Unix epochs) into buckets of the given granularity
+	// ("hour", "day", or "month").
+	GetStatistics(from, to int64, bucket string) ([]StatisticsBucket, error)
+
+	// UpdateSwarmSize records a fresh seeder/leecher reading for the
+	// torrent identified by infoHash, as obtained from a tracker scrape.
+	UpdateSwarmSize(infoHash []byte, seeders, leechers uint, updatedOn int64) error
+
+	Engine() Engine
+	Close() error
+}
+
+// MakeDatabase opens (creating and migrating, if necessary) the database
+// addressed by rawURL, dispatching to the backend named by its scheme, e.g.
+// "sqlite3:///var/lib/magnetico/database.sqlite3".
+func MakeDatabase(rawURL string) (Database, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: error while parsing rawURL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "sqlite3":
+		return makeSqlite3Database(u)
+	default:
+		return nil, fmt.Errorf("persistence: unknown backend %s", u.Scheme)
+	}
+}
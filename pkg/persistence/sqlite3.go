@@ -0,0 +1,170 @@
+package persistence
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqlite3Schema = `
+CREATE TABLE IF NOT EXISTS torrents (
+	id            INTEGER PRIMARY KEY,
+	info_hash     BLOB UNIQUE NOT NULL,
+	name          TEXT NOT NULL,
+	total_size    INTEGER NOT NULL,
+	discovered_on INTEGER NOT NULL,
+	n_files       INTEGER NOT NULL DEFAULT 1,
+	updated_on    INTEGER,
+	n_seeders     INTEGER,
+	n_leechers    INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS files (
+	id         INTEGER PRIMARY KEY,
+	torrent_id INTEGER NOT NULL REFERENCES torrents (id) ON DELETE CASCADE,
+	path       TEXT NOT NULL,
+	size       INTEGER NOT NULL
+);
+`
+
+// sqlite3Database is the sqlite3-backed persistence.Database.
+type sqlite3Database struct {
+	conn *sql.DB
+}
+
+func makeSqlite3Database(u *url.URL) (Database, error) {
+	dsn := u.Path
+	if dsn == "" {
+		dsn = u.Opaque
+	}
+
+	conn, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: error while opening sqlite3 database: %w", err)
+	}
+
+	if _, err = conn.Exec(sqlite3Schema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("persistence: error while migrating sqlite3 database: %w", err)
+	}
+
+	return &sqlite3Database{conn: conn}, nil
+}
+
+func (db *sqlite3Database) Engine() Engine {
+	return Sqlite3
+}
+
+func (db *sqlite3Database) Close() error {
+	return db.conn.Close()
+}
+
+// orderByColumn maps an OrderingCriteria to the torrents column it sorts
+// on. ByRelevance has no dedicated column: until this engine grows a
+// proper FTS5 virtual table, relevance-ordered queries fall back to
+// discovered_on so that at least free-text searches remain deterministic.
+func orderByColumn(orderBy OrderingCriteria) string {
+	switch orderBy {
+	case ByTotalSize:
+		return "total_size"
+	case ByNFiles:
+		return "n_files"
+	case ByUpdatedOn:
+		return "updated_on"
+	case ByNSeeders:
+		return "n_seeders"
+	case ByNLeechers:
+		return "n_leechers"
+	case ByRelevance, ByDiscoveredOn:
+		fallthrough
+	default:
+		return "discovered_on"
+	}
+}
+
+func (db *sqlite3Database) QueryTorrents(query string, epoch int64, orderBy OrderingCriteria, ascending bool,
+	limit uint, lastOrderedValue *float64, lastID *uint64) ([]Torrent, error) {
+	stmt, args := buildTorrentsQuery(query, epoch, orderBy, ascending, limit, lastOrderedValue, lastID)
+	return db.queryTorrents(stmt, args...)
+}
+
+// buildTorrentsQuery builds the SELECT (and its bind arguments) shared by
+// QueryTorrents and QueryTorrentsChan.
+func buildTorrentsQuery(query string, epoch int64, orderBy OrderingCriteria, ascending bool,
+	limit uint, lastOrderedValue *float64, lastID *uint64) (string, []interface{}) {
+	column := orderByColumn(orderBy)
+	direction := "DESC"
+	cmp := "<"
+	if ascending {
+		direction = "ASC"
+		cmp = ">"
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, 4)
+
+	sb.WriteString("SELECT info_hash, name, total_size, discovered_on, n_files, updated_on, n_seeders, n_leechers FROM torrents WHERE discovered_on <= ?")
+	args = append(args, epoch)
+
+	if query != "" {
+		sb.WriteString(" AND name LIKE ?")
+		args = append(args, "%"+query+"%")
+	}
+
+	if lastOrderedValue != nil && lastID != nil {
+		sb.WriteString(fmt.Sprintf(" AND (%s %s ? OR (%s = ? AND id %s ?))", column, cmp, column, cmp))
+		args = append(args, *lastOrderedValue, *lastOrderedValue, *lastID)
+	}
+
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, direction, direction))
+	args = append(args, limit)
+
+	return sb.String(), args
+}
+
+// queryTorrents runs stmt (with args) and scans every row into a Torrent.
+func (db *sqlite3Database) queryTorrents(stmt string, args ...interface{}) ([]Torrent, error) {
+	rows, err := db.conn.Query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: query error: %w", err)
+	}
+	defer rows.Close()
+
+	var torrents []Torrent
+	for rows.Next() {
+		t, err := scanTorrent(rows)
+		if err != nil {
+			return nil, err
+		}
+		torrents = append(torrents, t)
+	}
+	return torrents, rows.Err()
+}
+
+// rowScanner is the subset of *sql.Rows scanTorrent needs, so it can also
+// be used to scan a single *sql.Row-backed query.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTorrent(row rowScanner) (Torrent, error) {
+	var (
+		t         Torrent
+		updatedOn sql.NullInt64
+		nSeeders  sql.NullInt64
+		nLeechers sql.NullInt64
+	)
+
+	if err := row.Scan(&t.InfoHash, &t.Name, &t.Size, &t.DiscoveredOn, &t.NFiles, &updatedOn, &nSeeders, &nLeechers); err != nil {
+		return Torrent{}, fmt.Errorf("persistence: scan error: %w", err)
+	}
+
+	t.UpdatedOn = updatedOn.Int64
+	t.NSeeders = uint(nSeeders.Int64)
+	t.NLeechers = uint(nLeechers.Int64)
+
+	return t, nil
+}
@@ -0,0 +1,44 @@
+package persistence
+
+import "fmt"
+
+// bucketFormats maps the granularities GetStatistics accepts to the
+// strftime format SQLite groups rows by.
+var bucketFormats = map[string]string{
+	"hour":  "%Y-%m-%dT%H:00:00Z",
+	"day":   "%Y-%m-%d",
+	"month": "%Y-%m",
+}
+
+// GetStatistics aggregates torrents discovered between from and to
+// (inclusive Unix epochs) into buckets of the given granularity, returning
+// the torrent count, total indexed size, and average files-per-torrent for
+// each bucket that has at least one torrent in it.
+func (db *sqlite3Database) GetStatistics(from, to int64, bucket string) ([]StatisticsBucket, error) {
+	format, ok := bucketFormats[bucket]
+	if !ok {
+		return nil, fmt.Errorf("persistence: unknown statistics bucket: %s", bucket)
+	}
+
+	rows, err := db.conn.Query(
+		"SELECT strftime(?, discovered_on, 'unixepoch') AS bucket, "+
+			"COUNT(*), SUM(total_size), AVG(n_files) "+
+			"FROM torrents WHERE discovered_on BETWEEN ? AND ? "+
+			"GROUP BY bucket ORDER BY bucket ASC",
+		format, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: query error: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []StatisticsBucket
+	for rows.Next() {
+		var b StatisticsBucket
+		if err := rows.Scan(&b.Bucket, &b.NTorrents, &b.TotalSize, &b.AvgFilesPerTorrent); err != nil {
+			return nil, fmt.Errorf("persistence: scan error: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
@@ -0,0 +1,47 @@
+package persistence
+
+import "context"
+
+// QueryTorrentsChan is QueryTorrents' streaming counterpart: rows are
+// scanned and delivered one at a time as they come off the wire, instead
+// of being collected into a slice first. The producer goroutine stops and
+// releases torrents/errs and the underlying *sql.Rows as soon as ctx is
+// cancelled, even if nothing has drained the channels yet.
+func (db *sqlite3Database) QueryTorrentsChan(ctx context.Context, query string, epoch int64, orderBy OrderingCriteria, ascending bool,
+	limit uint, lastOrderedValue *float64, lastID *uint64) (<-chan Torrent, <-chan error) {
+	torrents := make(chan Torrent)
+	errs := make(chan error, 1)
+
+	stmt, args := buildTorrentsQuery(query, epoch, orderBy, ascending, limit, lastOrderedValue, lastID)
+
+	go func() {
+		defer close(torrents)
+		defer close(errs)
+
+		rows, err := db.conn.QueryContext(ctx, stmt, args...)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			t, err := scanTorrent(rows)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case torrents <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return torrents, errs
+}
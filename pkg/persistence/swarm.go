@@ -0,0 +1,17 @@
+package persistence
+
+import "fmt"
+
+// UpdateSwarmSize records a fresh seeder/leecher reading for the torrent
+// identified by infoHash, as obtained from a tracker scrape. It is a no-op
+// (not an error) if infoHash isn't a torrent we've discovered.
+func (db *sqlite3Database) UpdateSwarmSize(infoHash []byte, seeders, leechers uint, updatedOn int64) error {
+	_, err := db.conn.Exec(
+		"UPDATE torrents SET n_seeders = ?, n_leechers = ?, updated_on = ? WHERE info_hash = ?",
+		seeders, leechers, updatedOn, infoHash,
+	)
+	if err != nil {
+		return fmt.Errorf("persistence: error while updating swarm size: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetTorrent returns a single torrent's metadata by its infohash, or nil
+// (with no error) if no torrent with that infohash has been discovered.
+func (db *sqlite3Database) GetTorrent(infoHash []byte) (*Torrent, error) {
+	row := db.conn.QueryRow(
+		"SELECT info_hash, name, total_size, discovered_on, n_files, updated_on, n_seeders, n_leechers "+
+			"FROM torrents WHERE info_hash = ?",
+		infoHash,
+	)
+
+	t, err := scanTorrent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetFiles returns the file list belonging to the torrent identified by
+// infoHash, ordered the way they were discovered in.
+func (db *sqlite3Database) GetFiles(infoHash []byte) ([]File, error) {
+	rows, err := db.conn.Query(
+		"SELECT files.path, files.size FROM files "+
+			"JOIN torrents ON torrents.id = files.torrent_id "+
+			"WHERE torrents.info_hash = ? ORDER BY files.id",
+		infoHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: query error: %w", err)
+	}
+	defer rows.Close()
+
+	var files []File
+	for rows.Next() {
+		var f File
+		if err := rows.Scan(&f.Path, &f.Size); err != nil {
+			return nil, fmt.Errorf("persistence: scan error: %w", err)
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
@@ -0,0 +1,126 @@
+package scrape
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// decodeBencodeDict parses just enough of the bencode format to read a
+// tracker's "GET /scrape" response: a single top-level dictionary whose
+// values are either integers, byte strings, lists, or nested dictionaries.
+// It is not a general-purpose bencode decoder; it exists so pkg/scrape
+// doesn't need to pull in a full BitTorrent library just to read a handful
+// of integers out of a scrape reply.
+func decodeBencodeDict(data []byte) (map[string]interface{}, error) {
+	value, rest, err := decodeBencodeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("bencode: trailing data after top-level value")
+	}
+
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("bencode: top-level value is not a dictionary")
+	}
+	return dict, nil
+}
+
+func decodeBencodeValue(data []byte) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("bencode: unexpected end of input")
+	}
+
+	switch data[0] {
+	case 'i':
+		return decodeBencodeInt(data)
+	case 'l':
+		return decodeBencodeList(data)
+	case 'd':
+		return decodeBencodeDictValue(data)
+	default:
+		return decodeBencodeString(data)
+	}
+}
+
+func decodeBencodeInt(data []byte) (interface{}, []byte, error) {
+	end := indexByte(data, 'e')
+	if end < 0 {
+		return nil, nil, fmt.Errorf("bencode: unterminated integer")
+	}
+	n, err := strconv.ParseInt(string(data[1:end]), 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bencode: invalid integer: %w", err)
+	}
+	return n, data[end+1:], nil
+}
+
+func decodeBencodeString(data []byte) (interface{}, []byte, error) {
+	colon := indexByte(data, ':')
+	if colon < 0 {
+		return nil, nil, fmt.Errorf("bencode: malformed string length")
+	}
+	length, err := strconv.Atoi(string(data[:colon]))
+	if err != nil || length < 0 {
+		return nil, nil, fmt.Errorf("bencode: invalid string length")
+	}
+	start := colon + 1
+	if start+length > len(data) {
+		return nil, nil, fmt.Errorf("bencode: string length exceeds input")
+	}
+	return string(data[start : start+length]), data[start+length:], nil
+}
+
+func decodeBencodeList(data []byte) (interface{}, []byte, error) {
+	rest := data[1:]
+	var list []interface{}
+	for len(rest) == 0 || rest[0] != 'e' {
+		var (
+			value interface{}
+			err   error
+		)
+		value, rest, err = decodeBencodeValue(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		list = append(list, value)
+	}
+	return list, rest[1:], nil
+}
+
+func decodeBencodeDictValue(data []byte) (interface{}, []byte, error) {
+	rest := data[1:]
+	dict := make(map[string]interface{})
+	for len(rest) == 0 || rest[0] != 'e' {
+		var (
+			key   interface{}
+			value interface{}
+			err   error
+		)
+		key, rest, err = decodeBencodeValue(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("bencode: dictionary key is not a string")
+		}
+
+		value, rest, err = decodeBencodeValue(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		dict[keyStr] = value
+	}
+	return dict, rest[1:], nil
+}
+
+func indexByte(data []byte, b byte) int {
+	for i, c := range data {
+		if c == b {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,93 @@
+package scrape
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeBencodeDict(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "scrape-shaped response",
+			input: "d5:filesd20:aaaaaaaaaaaaaaaaaaaad8:completei5e10:incompletei2eeee",
+			want: map[string]interface{}{
+				"files": map[string]interface{}{
+					"aaaaaaaaaaaaaaaaaaaa": map[string]interface{}{
+						"complete":   int64(5),
+						"incomplete": int64(2),
+					},
+				},
+			},
+		},
+		{
+			name:  "empty dict",
+			input: "de",
+			want:  map[string]interface{}{},
+		},
+		{
+			name:  "list of integers",
+			input: "d5:listsli1ei2ei3eee",
+			want: map[string]interface{}{
+				"lists": []interface{}{int64(1), int64(2), int64(3)},
+			},
+		},
+		{
+			name:    "not a dictionary",
+			input:   "i5e",
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			input:   "deextra",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated integer",
+			input:   "d1:ai5",
+			wantErr: true,
+		},
+		{
+			name:    "malformed string length",
+			input:   "d1:aee",
+			wantErr: true,
+		},
+		{
+			name:    "string length exceeds input",
+			input:   "d1:a5:abee",
+			wantErr: true,
+		},
+		{
+			name:    "non-string dict key",
+			input:   "di1ei2ee",
+			wantErr: true,
+		},
+		{
+			name:    "empty input",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := decodeBencodeDict([]byte(c.input))
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("decodeBencodeDict(%q) = %v, want error", c.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeBencodeDict(%q) returned unexpected error: %v", c.input, err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("decodeBencodeDict(%q) = %#v, want %#v", c.input, got, c.want)
+			}
+		})
+	}
+}
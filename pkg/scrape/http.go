@@ -0,0 +1,88 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// scrapeHTTP performs a single HTTP(S) "GET /scrape" request against
+// announceURL for infoHash, per the convention most public trackers (that
+// predate BEP 15's UDP scrape) still implement.
+func scrapeHTTP(ctx context.Context, announceURL string, infoHash [20]byte) (*Result, error) {
+	scrapeURL, err := toScrapeURL(announceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	q := scrapeURL.Query()
+	q.Set("info_hash", string(infoHash[:]))
+	scrapeURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scrapeURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape: unexpected status %d from %s", resp.StatusCode, announceURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	dict, err := decodeBencodeDict(body)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: malformed response from %s: %w", announceURL, err)
+	}
+
+	files, ok := dict["files"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("scrape: %s returned no `files` dictionary", announceURL)
+	}
+
+	entry, ok := files[string(infoHash[:])].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("scrape: %s did not return an entry for this infohash", announceURL)
+	}
+
+	complete, _ := entry["complete"].(int64)
+	incomplete, _ := entry["incomplete"].(int64)
+
+	return &Result{
+		InfoHash:  infoHash,
+		Seeders:   uint(complete),
+		Leechers:  uint(incomplete),
+		ScrapedAt: time.Now(),
+	}, nil
+}
+
+// toScrapeURL converts a tracker's announce URL into its scrape URL by
+// replacing the last "/announce" path segment with "/scrape", as specified
+// by the (unofficial but universally followed) scrape convention.
+func toScrapeURL(announceURL string) (*url.URL, error) {
+	u, err := url.Parse(announceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	i := strings.LastIndex(u.Path, "/announce")
+	if i < 0 {
+		return nil, fmt.Errorf("scrape: %s has no /announce segment to rewrite", announceURL)
+	}
+	u.Path = u.Path[:i] + "/scrape" + u.Path[i+len("/announce"):]
+
+	return u, nil
+}
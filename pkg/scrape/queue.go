@@ -0,0 +1,45 @@
+package scrape
+
+import "time"
+
+// queueItem is a single infohash waiting for its next scrape, ordered by
+// dueAt in the Scraper's priority queue.
+type queueItem struct {
+	infoHash [20]byte
+	priority int
+	dueAt    time.Time
+	backoff  uint
+	index    int
+}
+
+// priorityQueue is a container/heap.Interface over queueItem, ordered so
+// that the infohash due soonest is always at the root.
+type priorityQueue []*queueItem
+
+func (pq priorityQueue) Len() int { return len(pq) }
+
+func (pq priorityQueue) Less(i, j int) bool {
+	return pq[i].dueAt.Before(pq[j].dueAt)
+}
+
+func (pq priorityQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].index = i
+	pq[j].index = j
+}
+
+func (pq *priorityQueue) Push(x interface{}) {
+	item := x.(*queueItem)
+	item.index = len(*pq)
+	*pq = append(*pq, item)
+}
+
+func (pq *priorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*pq = old[:n-1]
+	return item
+}
@@ -0,0 +1,242 @@
+// Package scrape implements a background scraper that periodically asks
+// BitTorrent trackers how many seeders and leechers are swarming around the
+// infohashes magnetico has already discovered, so that
+// persistence.ByNSeeders and persistence.ByNLeechers return results that
+// reflect reality instead of the zeros every torrent starts out with.
+//
+// Two tracker scrape protocols are supported: UDP scrape as defined by
+// BEP 15, and the older HTTP/HTTPS "GET /scrape" convention most public
+// trackers still answer to.
+package scrape
+
+import (
+	"container/heap"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/boramalper/magnetico/pkg/persistence"
+)
+
+// Result is what a single successful scrape tells us about one infohash.
+type Result struct {
+	InfoHash  [20]byte
+	Seeders   uint
+	Leechers  uint
+	ScrapedAt time.Time
+}
+
+// Scraper periodically scrapes a fixed list of trackers for the infohashes
+// fed to it, writing the results back through database.
+type Scraper struct {
+	database persistence.Database
+	trackers []string
+
+	mu       sync.Mutex
+	queue    priorityQueue
+	inQueue  map[[20]byte]*queueItem
+	limiters map[string]*rate.Limiter
+
+	// MinInterval and MaxInterval bound how often any single infohash may
+	// be rescraped: popular torrents (many recent requests) gravitate
+	// towards MinInterval, cold ones towards MaxInterval.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// NewScraper returns a Scraper that will poll trackers for seeder/leecher
+// counts of infohashes registered via Scraper.Enqueue, persisting updates
+// through database.
+func NewScraper(database persistence.Database, trackers []string) *Scraper {
+	return &Scraper{
+		database:    database,
+		trackers:    trackers,
+		inQueue:     make(map[[20]byte]*queueItem),
+		limiters:    make(map[string]*rate.Limiter),
+		MinInterval: 15 * time.Minute,
+		MaxInterval: 24 * time.Hour,
+	}
+}
+
+// Enqueue registers infoHash for scraping, or bumps its priority (making it
+// due for a rescrape sooner) if it is already queued. priority is typically
+// derived from how often the infohash shows up in user queries: higher
+// priority means more frequent rescrapes.
+func (s *Scraper) Enqueue(infoHash [20]byte, priority int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item, ok := s.inQueue[infoHash]; ok {
+		if priority > item.priority {
+			item.priority = priority
+			if dueAt := time.Now().Add(s.nextInterval(item)); dueAt.Before(item.dueAt) {
+				item.dueAt = dueAt
+			}
+			heap.Fix(&s.queue, item.index)
+		}
+		return
+	}
+
+	item := &queueItem{
+		infoHash: infoHash,
+		priority: priority,
+		dueAt:    time.Now(),
+	}
+	heap.Push(&s.queue, item)
+	s.inQueue[infoHash] = item
+}
+
+// Run blocks, dispatching due scrapes until ctx is cancelled.
+func (s *Scraper) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			for _, item := range s.due() {
+				go s.scrape(ctx, item)
+			}
+		}
+	}
+}
+
+// due pops every queued infohash whose dueAt has elapsed and re-enqueues it
+// for its next rescrape before returning the batch to the caller.
+func (s *Scraper) due() []*queueItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batch []*queueItem
+	now := time.Now()
+	for s.queue.Len() > 0 && s.queue[0].dueAt.Before(now) {
+		item := heap.Pop(&s.queue).(*queueItem)
+		batch = append(batch, item)
+
+		item.dueAt = now.Add(s.nextInterval(item))
+		heap.Push(&s.queue, item)
+	}
+	return batch
+}
+
+// nextInterval scales between MinInterval and MaxInterval based on
+// priority: a priority of zero or below always waits MaxInterval, while
+// higher priorities wait MaxInterval/(priority+1), never less than
+// MinInterval.
+func (s *Scraper) nextInterval(item *queueItem) time.Duration {
+	if item.priority <= 0 {
+		return s.MaxInterval
+	}
+
+	interval := s.MaxInterval / time.Duration(item.priority+1)
+	if interval < s.MinInterval {
+		return s.MinInterval
+	}
+	return interval
+}
+
+// scrape asks every configured tracker about infoHash, applying a
+// per-tracker-host rate limit and exponential backoff on failure, and
+// persists the best (highest seeder count) result it gets back.
+func (s *Scraper) scrape(ctx context.Context, item *queueItem) {
+	var best *Result
+
+	for _, tracker := range s.trackers {
+		limiter := s.limiterFor(tracker)
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		result, err := scrapeTracker(ctx, tracker, item.infoHash)
+		if err != nil {
+			s.backoff(item)
+			zap.L().Debug("scrape failed", zap.String("tracker", tracker), zap.Error(err))
+			continue
+		}
+
+		if best == nil || result.Seeders > best.Seeders {
+			best = result
+		}
+	}
+
+	if best == nil {
+		return
+	}
+
+	s.mu.Lock()
+	item.backoff = 0
+	s.mu.Unlock()
+
+	if err := s.database.UpdateSwarmSize(best.InfoHash[:], best.Seeders, best.Leechers, best.ScrapedAt.Unix()); err != nil {
+		zap.L().Warn("couldn't persist scrape result", zap.Error(err))
+	}
+}
+
+// backoff delays item's next scrape exponentially, capped at MaxInterval,
+// so a tracker that is down doesn't get hammered every tick.
+func (s *Scraper) backoff(item *queueItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item.backoff++
+	delay := s.MinInterval << item.backoff
+	if delay > s.MaxInterval || delay <= 0 {
+		delay = s.MaxInterval
+	}
+	item.dueAt = time.Now().Add(delay)
+	heap.Fix(&s.queue, item.index)
+}
+
+// limiterFor returns the rate.Limiter for tracker's host, creating one
+// limited to one request every two seconds if this is the first time we
+// talk to it.
+func (s *Scraper) limiterFor(tracker string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	host := trackerHost(tracker)
+	limiter, ok := s.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(2*time.Second), 1)
+		s.limiters[host] = limiter
+	}
+	return limiter
+}
+
+func trackerHost(tracker string) string {
+	u, err := url.Parse(tracker)
+	if err != nil {
+		return tracker
+	}
+	return u.Host
+}
+
+// scrapeTracker dispatches to the UDP (BEP 15) or HTTP scrape
+// implementation depending on tracker's scheme.
+func scrapeTracker(ctx context.Context, tracker string, infoHash [20]byte) (*Result, error) {
+	u, err := url.Parse(tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "udp":
+		return scrapeUDP(ctx, u.Host, infoHash)
+	case "http", "https":
+		return scrapeHTTP(ctx, tracker, infoHash)
+	default:
+		return nil, &net.AddrError{Err: "unsupported tracker scheme", Addr: tracker}
+	}
+}
+
+// httpClient is shared across HTTP scrapes so connections can be reused.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
@@ -0,0 +1,106 @@
+package scrape
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+const (
+	udpConnectMagic  uint64 = 0x41727101980
+	udpActionConnect uint32 = 0
+	udpActionScrape  uint32 = 2
+)
+
+// scrapeUDP performs a single BEP 15 UDP scrape of host for infoHash: a
+// connect handshake followed by a scrape request carrying exactly one
+// infohash.
+func scrapeUDP(ctx context.Context, host string, infoHash [20]byte) (*Result, error) {
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	connectionID, err := udpConnect(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return udpScrape(conn, connectionID, infoHash)
+}
+
+func udpConnect(conn net.Conn) (uint64, error) {
+	transactionID := rand.Uint32()
+
+	req := make([]byte, 16)
+	binary.BigEndian.PutUint64(req[0:8], udpConnectMagic)
+	binary.BigEndian.PutUint32(req[8:12], udpActionConnect)
+	binary.BigEndian.PutUint32(req[12:16], transactionID)
+
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 16)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	if n < 16 {
+		return 0, fmt.Errorf("scrape: short connect response (%d bytes)", n)
+	}
+	if action := binary.BigEndian.Uint32(resp[0:4]); action != udpActionConnect {
+		return 0, fmt.Errorf("scrape: unexpected action %d in connect response", action)
+	}
+	if binary.BigEndian.Uint32(resp[4:8]) != transactionID {
+		return 0, fmt.Errorf("scrape: transaction ID mismatch in connect response")
+	}
+
+	return binary.BigEndian.Uint64(resp[8:16]), nil
+}
+
+func udpScrape(conn net.Conn, connectionID uint64, infoHash [20]byte) (*Result, error) {
+	transactionID := rand.Uint32()
+
+	req := make([]byte, 16+20)
+	binary.BigEndian.PutUint64(req[0:8], connectionID)
+	binary.BigEndian.PutUint32(req[8:12], udpActionScrape)
+	binary.BigEndian.PutUint32(req[12:16], transactionID)
+	copy(req[16:36], infoHash[:])
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, 8+12)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	if n < 20 {
+		return nil, fmt.Errorf("scrape: short scrape response (%d bytes)", n)
+	}
+	if action := binary.BigEndian.Uint32(resp[0:4]); action != udpActionScrape {
+		return nil, fmt.Errorf("scrape: unexpected action %d in scrape response", action)
+	}
+	if binary.BigEndian.Uint32(resp[4:8]) != transactionID {
+		return nil, fmt.Errorf("scrape: transaction ID mismatch in scrape response")
+	}
+
+	return &Result{
+		InfoHash:  infoHash,
+		Seeders:   uint(binary.BigEndian.Uint32(resp[8:12])),
+		Leechers:  uint(binary.BigEndian.Uint32(resp[16:20])),
+		ScrapedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,105 @@
+package scrape
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeUDPTracker answers exactly one connect request and one scrape
+// request the way a real BEP 15 tracker would, using whatever
+// seeders/leechers the test supplies, then exits.
+func fakeUDPTracker(t *testing.T, seeders, leechers uint32) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	go func() {
+		buf := make([]byte, 1024)
+
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil || n < 16 {
+			return
+		}
+		transactionID := binary.BigEndian.Uint32(buf[12:16])
+
+		connResp := make([]byte, 16)
+		binary.BigEndian.PutUint32(connResp[0:4], udpActionConnect)
+		binary.BigEndian.PutUint32(connResp[4:8], transactionID)
+		binary.BigEndian.PutUint64(connResp[8:16], 0xdeadbeef)
+		if _, err := conn.WriteToUDP(connResp, addr); err != nil {
+			return
+		}
+
+		n, addr, err = conn.ReadFromUDP(buf)
+		if err != nil || n < 36 {
+			return
+		}
+		transactionID = binary.BigEndian.Uint32(buf[12:16])
+
+		scrapeResp := make([]byte, 20)
+		binary.BigEndian.PutUint32(scrapeResp[0:4], udpActionScrape)
+		binary.BigEndian.PutUint32(scrapeResp[4:8], transactionID)
+		binary.BigEndian.PutUint32(scrapeResp[8:12], seeders)
+		binary.BigEndian.PutUint32(scrapeResp[16:20], leechers)
+		conn.WriteToUDP(scrapeResp, addr)
+	}()
+
+	return conn
+}
+
+func TestScrapeUDP(t *testing.T) {
+	srv := fakeUDPTracker(t, 7, 3)
+	defer srv.Close()
+
+	var infoHash [20]byte
+	copy(infoHash[:], "abcdefghijklmnopqrst")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := scrapeUDP(ctx, srv.LocalAddr().String(), infoHash)
+	if err != nil {
+		t.Fatalf("scrapeUDP returned unexpected error: %v", err)
+	}
+	if result.Seeders != 7 || result.Leechers != 3 {
+		t.Fatalf("scrapeUDP result = %+v, want seeders=7 leechers=3", result)
+	}
+	if result.InfoHash != infoHash {
+		t.Fatalf("scrapeUDP result.InfoHash = %x, want %x", result.InfoHash, infoHash)
+	}
+}
+
+func TestUDPConnectTransactionIDMismatch(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1024)
+		_, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, 16)
+		binary.BigEndian.PutUint32(resp[0:4], udpActionConnect)
+		binary.BigEndian.PutUint32(resp[4:8], 0) // wrong transaction ID
+		binary.BigEndian.PutUint64(resp[8:16], 1)
+		conn.WriteToUDP(resp, addr)
+	}()
+
+	var infoHash [20]byte
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := scrapeUDP(ctx, conn.LocalAddr().String(), infoHash); err == nil {
+		t.Fatal("scrapeUDP succeeded despite a transaction ID mismatch")
+	}
+}